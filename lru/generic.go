@@ -0,0 +1,397 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"container/list"
+	"time"
+)
+
+// entryOf is a cache entry: it stores a key/value pair without boxing the
+// value in an interface{}.
+// entryOf是缓存中的一个entry,value不再需要装箱成interface{}
+type entryOf[K comparable, V any] struct {
+	key   K
+	value V
+
+	// expiresAt is the time at which this entry becomes stale. The zero
+	// Time means the entry never expires.
+	// expiresAt是这个entry的过期时间,零值表示永不过期
+	expiresAt time.Time
+	// heapIdx is this entry's index in the expiry heap, or -1 if it
+	// isn't in the heap (i.e. expiresAt is zero).
+	// heapIdx是这个entry在过期小顶堆中的下标,-1表示不在堆里(即永不过期)
+	heapIdx int
+
+	// cost is this entry's weight towards usedBytes, as computed by
+	// Coster at insertion time. Zero if Coster is nil.
+	// cost是这个entry对usedBytes的占用量,由Coster在插入时计算得到,
+	// Coster为nil时cost为零
+	cost int64
+}
+
+func (e *entryOf[K, V]) hasTTL() bool {
+	return !e.expiresAt.IsZero()
+}
+
+func (e *entryOf[K, V]) expired(now time.Time) bool {
+	return e.hasTTL() && !now.Before(e.expiresAt)
+}
+
+// CacheOf is a generic LRU cache. It is the typed core that the untyped
+// Cache is now built on top of, and it is not safe for concurrent access.
+// CacheOf是泛型版本的LRU cache,Cache现在是它的一个类型别名,同样不是并发安全的
+type CacheOf[K comparable, V any] struct {
+	// MaxEntries is the maximum number of cache entries before
+	// an item is evicted. Zero means no limit.
+	// 最大入口数,超过了就会触发淘汰,0表示没有限制
+	MaxEntries int
+
+	// OnEvicted optionally specifies a callback function to be
+	// executed when an entry is purged from the cache.
+	// 销毁前的回调
+	OnEvicted func(key K, value V)
+
+	// OnEvictedReason is like OnEvicted, but additionally reports why the
+	// entry was purged (LRU eviction, TTL expiration, ...). It is called
+	// alongside OnEvicted, not instead of it.
+	// OnEvictedReason和OnEvicted类似,但是会附带上本次淘汰的原因(LRU淘汰、
+	// TTL过期等),它和OnEvicted是一起被调用的,不是互斥关系
+	OnEvictedReason func(key K, value V, reason EvictReason)
+
+	// DefaultTTL is the TTL applied by Add when no explicit TTL is given
+	// via AddWithTTL. Zero means entries added through Add never expire.
+	// DefaultTTL是Add在没有通过AddWithTTL指定TTL时使用的默认过期时间,
+	// 零值表示通过Add添加的entry永不过期
+	DefaultTTL time.Duration
+
+	// Policy selects the admission/eviction policy. The zero value,
+	// PolicyLRU, is pure recency, exactly as before.
+	// Policy用于选择准入/淘汰策略,零值PolicyLRU表示和之前一样的纯LRU策略
+	Policy Policy
+
+	// Hasher hashes keys for PolicyTinyLFU's frequency sketch. If nil,
+	// keys are hashed with FNV-1a over their fmt.Sprint representation.
+	// Hasher用于为PolicyTinyLFU的频率草图计算key的哈希值,为nil时
+	// 使用key的fmt.Sprint表示做FNV-1a哈希
+	Hasher func(key K) uint64
+
+	// MaxBytes is the maximum total cost, as reported by Coster, of the
+	// values held by the cache before entries are evicted. Zero means no
+	// byte budget; only MaxEntries (if set) bounds the cache.
+	// MaxBytes是缓存中所有value按Coster计算出的总开销上限,超过了就会触发
+	// 淘汰;零值表示不设置字节预算,只受MaxEntries(如果设置了的话)约束
+	MaxBytes int64
+
+	// Coster optionally computes the cost of a key/value pair towards
+	// MaxBytes, e.g. len(value) for a []byte cache. If nil, entries cost
+	// nothing and MaxBytes has no effect.
+	// Coster用于计算一个key/value对相对于MaxBytes的开销,比如对于[]byte
+	// 类型的缓存可以用len(value);为nil时entry开销都算0,MaxBytes不起作用
+	Coster func(key K, value V) int64
+
+	// OnEvictedCost is like OnEvicted, but additionally reports the cost,
+	// as computed by Coster, of the entry being purged. It is called
+	// alongside OnEvicted and OnEvictedReason, not instead of them.
+	// OnEvictedCost和OnEvicted类似,但是会附带上被淘汰entry由Coster算出
+	// 的开销,它和OnEvicted、OnEvictedReason是一起被调用的,不是互斥关系
+	OnEvictedCost func(key K, value V, cost int64)
+
+	ll    *list.List
+	cache map[K]*list.Element
+
+	// usedBytes is the running sum of every live entry's cost.
+	// usedBytes是当前所有entry开销的累加值
+	usedBytes int64
+
+	// sketch is PolicyTinyLFU's frequency estimator, created lazily the
+	// first time it's needed.
+	// sketch是PolicyTinyLFU使用的频率估计器,在第一次需要时才会被创建
+	sketch *countMinSketch
+
+	// expHeap is a min-heap of the elements of ll that carry a TTL,
+	// ordered by expiresAt, used by the janitor to sweep expired entries
+	// in O(k log n) instead of scanning the whole cache.
+	// expHeap是ll中带有TTL的entry按过期时间组成的小顶堆,
+	// janitor依靠它以O(k log n)的代价清理过期数据,而不用扫描整个缓存
+	expHeap expHeapOf[K, V]
+}
+
+// NewOf creates a new CacheOf.
+// If maxEntries is zero, the cache has no limit and it's assumed
+// that eviction is done by the caller.
+// 创建一个CacheOf实例,maxEntries为0表示没有限制
+func NewOf[K comparable, V any](maxEntries int) *CacheOf[K, V] {
+	return &CacheOf[K, V]{
+		MaxEntries: maxEntries,
+		ll:         list.New(),
+		cache:      make(map[K]*list.Element),
+	}
+}
+
+// Add adds a value to the cache, using DefaultTTL as its expiration (zero
+// DefaultTTL means the entry never expires).
+// 往缓冲中增加一个值,过期时间为DefaultTTL(DefaultTTL为零值表示永不过期)
+func (c *CacheOf[K, V]) Add(key K, value V) {
+	c.AddWithTTL(key, value, c.DefaultTTL)
+}
+
+// AddWithTTL adds a value to the cache with its own expiration, overriding
+// DefaultTTL for this entry. A zero or negative ttl means the entry never
+// expires.
+// AddWithTTL往缓冲中增加一个值,并为这个entry单独指定过期时间,
+// 会覆盖DefaultTTL;ttl为零值或负值表示永不过期
+func (c *CacheOf[K, V]) AddWithTTL(key K, value V, ttl time.Duration) {
+	if c.cache == nil {
+		c.cache = make(map[K]*list.Element)
+		c.ll = list.New()
+	}
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = time.Now().Add(ttl)
+	}
+	var cost int64
+	if c.Coster != nil {
+		cost = c.Coster(key, value)
+	}
+	if ee, ok := c.cache[key]; ok {
+		c.ll.MoveToFront(ee)
+		en := ee.Value.(*entryOf[K, V])
+		en.value = value
+		c.usedBytes += cost - en.cost
+		en.cost = cost
+		c.setExpiry(ee, en, expiresAt)
+		if c.Policy == PolicyTinyLFU {
+			c.recordAccess(key)
+		}
+		// Overwriting an existing key with a pricier value can push
+		// usedBytes over MaxBytes just as much as inserting a new one
+		// can, so this path needs the same eviction loop below.
+		// 用开销更大的value覆盖已有key,和插入新entry一样可能导致usedBytes
+		// 超过MaxBytes,所以这条路径也需要和下面一样的淘汰循环
+		c.evictToFit()
+		return
+	}
+	// Under PolicyTinyLFU, a brand-new key competing for the last slot
+	// only gets in if it's estimated to be at least as hot as the entry it
+	// would evict; otherwise the cache is left untouched, which is what
+	// keeps a one-off scan from flushing out frequently reused entries.
+	// 在PolicyTinyLFU下,一个全新的key如果要挤占最后一个位置,只有在它的
+	// 估计访问频率不低于将被淘汰的entry时才会被准入;否则缓存保持不变,
+	// 这正是避免一次性扫描把高频entry冲刷掉的关键
+	if c.Policy == PolicyTinyLFU && c.MaxEntries != 0 && c.ll.Len() >= c.MaxEntries {
+		if victim := c.ll.Back(); victim != nil && !c.admit(key, victim) {
+			c.recordAccess(key)
+			return
+		}
+	}
+	en := &entryOf[K, V]{key: key, value: value, heapIdx: -1, cost: cost}
+	ele := c.ll.PushFront(en)
+	c.cache[key] = ele
+	c.usedBytes += cost
+	c.setExpiry(ele, en, expiresAt)
+	if c.Policy == PolicyTinyLFU {
+		c.recordAccess(key)
+	}
+	c.evictToFit()
+}
+
+// evictToFit evicts from the tail until both MaxEntries and MaxBytes are
+// satisfied. A single oversized value (whether from a new entry or from
+// overwriting an existing key) can blow through MaxBytes by more than one
+// entry's worth, so unlike the MaxEntries-only case this needs a loop
+// rather than a single RemoveOldest call.
+// evictToFit从链表尾部循环淘汰,直到同时满足MaxEntries和MaxBytes的约束。
+// 一个超大的value(不管是来自新entry还是覆盖已有key)可能一次性就超出
+// MaxBytes不止一个entry的量,所以和只有MaxEntries的情况不同,
+// 这里需要循环淘汰而不是只调用一次RemoveOldest
+func (c *CacheOf[K, V]) evictToFit() {
+	for c.ll.Len() > 0 && ((c.MaxEntries != 0 && c.ll.Len() > c.MaxEntries) || (c.MaxBytes != 0 && c.usedBytes > c.MaxBytes)) {
+		c.RemoveOldest()
+	}
+}
+
+// Get looks up a key's value from the cache. An entry whose TTL has
+// elapsed is treated as a miss and is evicted on the spot.
+// 根据key查找到value;如果entry的TTL已经到期,则视为未命中,并就地淘汰掉它
+func (c *CacheOf[K, V]) Get(key K) (value V, ok bool) {
+	if c.cache == nil {
+		return
+	}
+	if ele, hit := c.cache[key]; hit {
+		en := ele.Value.(*entryOf[K, V])
+		if en.expired(time.Now()) {
+			c.removeElement(ele, EvictReasonExpired)
+			return
+		}
+		c.ll.MoveToFront(ele)
+		if c.Policy == PolicyTinyLFU {
+			c.recordAccess(key)
+		}
+		return en.value, true
+	}
+	return
+}
+
+// GetWithExpiration looks up a key's value from the cache and additionally
+// reports the time at which it will expire. The zero Time means the entry
+// has no TTL. Like Get, an already-expired entry is reported as a miss and
+// evicted on the spot.
+// GetWithExpiration在查找value的同时返回entry的过期时间,零值Time表示
+// 这个entry没有设置TTL;和Get一样,已经过期的entry会被视为未命中并就地淘汰
+func (c *CacheOf[K, V]) GetWithExpiration(key K) (value V, expiration time.Time, ok bool) {
+	if c.cache == nil {
+		return
+	}
+	if ele, hit := c.cache[key]; hit {
+		en := ele.Value.(*entryOf[K, V])
+		if en.expired(time.Now()) {
+			c.removeElement(ele, EvictReasonExpired)
+			return
+		}
+		c.ll.MoveToFront(ele)
+		if c.Policy == PolicyTinyLFU {
+			c.recordAccess(key)
+		}
+		return en.value, en.expiresAt, true
+	}
+	return
+}
+
+// Peek returns the value associated with key without updating its
+// recency, so it won't affect what RemoveOldest evicts next. As with Get,
+// an entry whose TTL has already elapsed is reported as a miss and
+// evicted on the spot.
+// Peek查找value但不会更新它的访问顺序,因此不会影响RemoveOldest接下来
+// 淘汰的对象;和Get一样,TTL已经到期的entry会被视为未命中并就地淘汰
+func (c *CacheOf[K, V]) Peek(key K) (value V, ok bool) {
+	if c.cache == nil {
+		return
+	}
+	if ele, hit := c.cache[key]; hit {
+		en := ele.Value.(*entryOf[K, V])
+		if en.expired(time.Now()) {
+			c.removeElement(ele, EvictReasonExpired)
+			return
+		}
+		return en.value, true
+	}
+	return
+}
+
+// Contains reports whether key is in the cache, without the recency
+// update that Get performs.
+// Contains判断key是否在缓存中,但不会像Get那样更新它的访问顺序
+func (c *CacheOf[K, V]) Contains(key K) bool {
+	_, ok := c.Peek(key)
+	return ok
+}
+
+// Remove removes the provided key from the cache.
+func (c *CacheOf[K, V]) Remove(key K) {
+	if c.cache == nil {
+		return
+	}
+	if ele, hit := c.cache[key]; hit {
+		c.removeElement(ele, EvictReasonRemoved)
+	}
+}
+
+// RemoveOldest removes the oldest item from the cache.
+// 删除最久的
+func (c *CacheOf[K, V]) RemoveOldest() {
+	if c.cache == nil {
+		return
+	}
+	ele := c.ll.Back()
+	if ele != nil {
+		c.removeElement(ele, EvictReasonCapacity)
+	}
+}
+
+func (c *CacheOf[K, V]) removeElement(e *list.Element, reason EvictReason) {
+	c.ll.Remove(e)
+	kv := e.Value.(*entryOf[K, V])
+	if kv.hasTTL() {
+		c.expHeap.remove(kv.heapIdx)
+	}
+	delete(c.cache, kv.key)
+	c.usedBytes -= kv.cost
+	if c.OnEvicted != nil {
+		c.OnEvicted(kv.key, kv.value)
+	}
+	if c.OnEvictedReason != nil {
+		c.OnEvictedReason(kv.key, kv.value, reason)
+	}
+	if c.OnEvictedCost != nil {
+		c.OnEvictedCost(kv.key, kv.value, kv.cost)
+	}
+}
+
+// setExpiry updates en's expiresAt and keeps the expiry heap in sync:
+// entries gain, lose or adjust their heap position as their TTL changes.
+// setExpiry更新en的过期时间,并同步维护过期堆:entry的TTL发生变化时,
+// 它在堆中可能需要入堆、出堆或者调整位置
+func (c *CacheOf[K, V]) setExpiry(ele *list.Element, en *entryOf[K, V], expiresAt time.Time) {
+	hadTTL := en.hasTTL()
+	en.expiresAt = expiresAt
+	switch {
+	case !hadTTL && en.hasTTL():
+		c.expHeap.push(ele)
+	case hadTTL && !en.hasTTL():
+		c.expHeap.remove(en.heapIdx)
+	case hadTTL && en.hasTTL():
+		c.expHeap.fix(en.heapIdx)
+	}
+}
+
+// Len returns the number of items in the cache.
+func (c *CacheOf[K, V]) Len() int {
+	if c.cache == nil {
+		return 0
+	}
+	return c.ll.Len()
+}
+
+// Bytes returns the total cost, as computed by Coster, of the values
+// currently held by the cache.
+// Bytes返回当前缓存中所有value按Coster计算出的总开销
+func (c *CacheOf[K, V]) Bytes() int64 {
+	return c.usedBytes
+}
+
+// Clear purges all stored items from the cache.
+func (c *CacheOf[K, V]) Clear() {
+	if c.OnEvicted != nil || c.OnEvictedReason != nil || c.OnEvictedCost != nil {
+		for _, e := range c.cache {
+			kv := e.Value.(*entryOf[K, V])
+			if c.OnEvicted != nil {
+				c.OnEvicted(kv.key, kv.value)
+			}
+			if c.OnEvictedReason != nil {
+				c.OnEvictedReason(kv.key, kv.value, EvictReasonClear)
+			}
+			if c.OnEvictedCost != nil {
+				c.OnEvictedCost(kv.key, kv.value, kv.cost)
+			}
+		}
+	}
+	c.ll = nil
+	c.cache = nil
+	c.expHeap = nil
+	c.usedBytes = 0
+}
@@ -0,0 +1,188 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import "testing"
+
+func TestCacheOfGetMiss(t *testing.T) {
+	c := NewOf[string, int](0)
+	if _, ok := c.Get("missing"); ok {
+		t.Fatalf("Get(missing) ok = true, want false")
+	}
+}
+
+func TestCacheOfAddAndGet(t *testing.T) {
+	c := NewOf[string, int](0)
+	c.Add("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if c.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", c.Len())
+	}
+}
+
+func TestCacheOfEvictsOldest(t *testing.T) {
+	var evicted []string
+	c := NewOf[string, int](2)
+	c.OnEvicted = func(key string, value int) {
+		evicted = append(evicted, key)
+	}
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("c", 3)
+	if c.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", c.Len())
+	}
+	if len(evicted) != 1 || evicted[0] != "a" {
+		t.Fatalf("evicted = %v, want [a]", evicted)
+	}
+	if c.Contains("a") {
+		t.Fatalf("a should have been evicted")
+	}
+}
+
+func TestCacheOfGetPromotesRecency(t *testing.T) {
+	var evicted []string
+	c := NewOf[string, int](2)
+	c.OnEvicted = func(key string, value int) {
+		evicted = append(evicted, key)
+	}
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Get("a") // a is now more recent than b
+	c.Add("c", 3)
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Fatalf("evicted = %v, want [b]", evicted)
+	}
+}
+
+func TestCacheOfPeekDoesNotPromote(t *testing.T) {
+	var evicted []string
+	c := NewOf[string, int](2)
+	c.OnEvicted = func(key string, value int) {
+		evicted = append(evicted, key)
+	}
+	c.Add("a", 1)
+	c.Add("b", 2)
+	if _, ok := c.Peek("a"); !ok {
+		t.Fatalf("Peek(a) ok = false, want true")
+	}
+	c.Add("c", 3)
+	if len(evicted) != 1 || evicted[0] != "a" {
+		t.Fatalf("evicted = %v, want [a] since Peek must not change recency", evicted)
+	}
+}
+
+func TestCacheOfRemove(t *testing.T) {
+	c := NewOf[string, int](0)
+	c.Add("a", 1)
+	c.Remove("a")
+	if c.Contains("a") {
+		t.Fatalf("a should have been removed")
+	}
+	if c.Len() != 0 {
+		t.Fatalf("Len() = %d, want 0", c.Len())
+	}
+}
+
+func TestCacheOfClear(t *testing.T) {
+	var evicted []string
+	c := NewOf[string, int](0)
+	c.OnEvictedReason = func(key string, value int, reason EvictReason) {
+		evicted = append(evicted, key)
+		if reason != EvictReasonClear {
+			t.Fatalf("reason = %v, want EvictReasonClear", reason)
+		}
+	}
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Clear()
+	if c.Len() != 0 {
+		t.Fatalf("Len() = %d after Clear, want 0", c.Len())
+	}
+	if len(evicted) != 2 {
+		t.Fatalf("evicted = %v, want both keys reported", evicted)
+	}
+}
+
+// TestCacheAliasLiteralConstruction guards against Cache regressing to a
+// pointer-embedded wrapper, which breaks struct literals that set fields
+// like OnEvicted directly, the same pattern groupcache itself uses.
+func TestCacheAliasLiteralConstruction(t *testing.T) {
+	var evicted []Key
+	c := &Cache{
+		MaxEntries: 2,
+		OnEvicted: func(key Key, value interface{}) {
+			evicted = append(evicted, key)
+		},
+	}
+	c.Add("a", 1)
+	c.Add("b", 2)
+	c.Add("c", 3)
+	if len(evicted) != 1 || evicted[0] != Key("a") {
+		t.Fatalf("evicted = %v, want [a]", evicted)
+	}
+}
+
+// TestCacheAliasZeroValue guards against Cache regressing to a
+// pointer-embedded wrapper, which panics on first use from a zero value
+// instead of lazily initializing like the pre-generics Cache did.
+func TestCacheAliasZeroValue(t *testing.T) {
+	var c Cache
+	c.Add("x", 1)
+	if v, ok := c.Get("x"); !ok || v != 1 {
+		t.Fatalf("Get(x) = %v, %v; want 1, true", v, ok)
+	}
+}
+
+func TestMaxBytesEvictsOnInsert(t *testing.T) {
+	c := NewOf[string, string](0)
+	c.MaxBytes = 10
+	c.Coster = func(key string, value string) int64 { return int64(len(value)) }
+
+	c.Add("a", "12345")
+	c.Add("b", "12345")
+	if got := c.Bytes(); got != 10 {
+		t.Fatalf("Bytes() = %d, want 10", got)
+	}
+
+	c.Add("c", "1234567890")
+	if got := c.Bytes(); got > 10 {
+		t.Fatalf("Bytes() = %d after insert, want <= 10", got)
+	}
+	if c.Contains("a") {
+		t.Fatalf("oldest entry should have been evicted to stay under MaxBytes")
+	}
+}
+
+// TestMaxBytesEvictsOnOverwrite guards against overwriting an existing key
+// with a much larger value leaving the cache over MaxBytes until some
+// unrelated future insert happens to trigger eviction.
+func TestMaxBytesEvictsOnOverwrite(t *testing.T) {
+	c := NewOf[string, string](0)
+	c.MaxBytes = 10
+	c.Coster = func(key string, value string) int64 { return int64(len(value)) }
+
+	c.Add("a", "12345")
+	c.Add("b", "12345")
+	c.Add("b", "1234567890123456789012345")
+
+	if got := c.Bytes(); got > 10 {
+		t.Fatalf("Bytes() = %d after overwrite, want <= 10", got)
+	}
+}
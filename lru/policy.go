@@ -0,0 +1,193 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"container/list"
+	"fmt"
+	"hash/fnv"
+)
+
+// Policy selects how CacheOf decides which entries to keep once it's at
+// capacity.
+// Policy用于选择缓存到达容量上限后如何决定保留哪些entry
+type Policy int
+
+const (
+	// PolicyLRU evicts the least-recently-used entry. This is the
+	// default, and behaves exactly as CacheOf always has.
+	// PolicyLRU淘汰最久未使用的entry,是默认策略,行为和一直以来的CacheOf完全一样
+	PolicyLRU Policy = iota
+
+	// PolicyTinyLFU adds a frequency sketch (a small Count-Min Sketch)
+	// alongside the LRU list. When the cache is full, a brand-new key
+	// is only admitted if it's estimated to be accessed at least as often
+	// as the LRU-tail entry it would replace; otherwise the newcomer is
+	// dropped and the existing entry stays. This guards against the
+	// classic LRU weakness where one large sequential scan evicts an
+	// entire working set of frequently reused keys.
+	// PolicyTinyLFU在LRU链表之外维护了一个频率草图(一个小型的Count-Min
+	// Sketch)。当缓存已满时,一个全新的key只有在其估计访问频率不低于将被
+	// 替换的LRU尾部entry时才会被准入;否则这个新key会被直接丢弃,原有的
+	// entry保持不变。这可以避免LRU的经典弱点:一次大的顺序扫描把一整套
+	// 经常被复用的key全部淘汰掉
+	PolicyTinyLFU
+)
+
+// sketchRows is the number of independent hash functions the Count-Min
+// Sketch uses, i.e. its depth.
+// sketchRows是Count-Min Sketch使用的独立哈希函数个数,即草图的深度
+const sketchRows = 4
+
+// countMinSketch is a Count-Min Sketch over 4-bit saturating counters
+// (each counter is stored as a uint8 but capped at 15), used to estimate
+// how often a key has been seen recently. Periodically halving every
+// counter lets old frequencies decay instead of accumulating forever.
+// countMinSketch是一个基于4-bit饱和计数器的Count-Min Sketch(每个计数器
+// 用一个uint8存储,但上限是15),用来估计一个key最近被访问的频率;
+// 周期性地把所有计数器减半,可以让旧的频率随时间衰减,而不是无限累积
+type countMinSketch struct {
+	width          int
+	rows           [][]uint8
+	seeds          [sketchRows]uint64
+	total          int
+	resetThreshold int
+}
+
+// newCountMinSketch creates a sketch roughly width counters wide per row,
+// resetting (halving every counter) once total increments reach 10x that
+// width.
+// newCountMinSketch创建一个每行大约width个计数器的草图,
+// 累计增量达到width的10倍时会触发一次重置(所有计数器减半)
+func newCountMinSketch(width int) *countMinSketch {
+	if width < 64 {
+		width = 64
+	}
+	rows := make([][]uint8, sketchRows)
+	for i := range rows {
+		rows[i] = make([]uint8, width)
+	}
+	return &countMinSketch{
+		width: width,
+		rows:  rows,
+		seeds: [sketchRows]uint64{
+			0x9e3779b97f4a7c15, 0xbf58476d1ce4e5b9,
+			0x94d049bb133111eb, 0xd6e8feb86659fd93,
+		},
+		resetThreshold: width * 10,
+	}
+}
+
+// mix64 is a 64-bit finalizer (MurmurHash3's fmix64) used to turn h^seed
+// into a well-distributed counter index within a row.
+// mix64是一个64位的finalizer混合函数(来自MurmurHash3的fmix64),用来把h^seed
+// 混合成分布均匀的行内计数器下标
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+func (s *countMinSketch) indexes(h uint64) [sketchRows]int {
+	var idx [sketchRows]int
+	for i, seed := range s.seeds {
+		idx[i] = int(mix64(h^seed) % uint64(s.width))
+	}
+	return idx
+}
+
+// add increments the counters for h, capping each at 15, and halves every
+// counter once resetThreshold increments have accumulated.
+// add增加h对应的计数器,每个计数器上限为15;累计增量达到resetThreshold时
+// 会把所有计数器减半
+func (s *countMinSketch) add(h uint64) {
+	for i, idx := range s.indexes(h) {
+		if s.rows[i][idx] < 15 {
+			s.rows[i][idx]++
+		}
+	}
+	s.total++
+	if s.total >= s.resetThreshold {
+		for _, row := range s.rows {
+			for i := range row {
+				row[i] /= 2
+			}
+		}
+		s.total = 0
+	}
+}
+
+// estimate returns the minimum counter across all rows for h, the
+// standard Count-Min Sketch frequency estimate.
+// estimate返回h在所有行上计数器的最小值,这是Count-Min Sketch的标准频率估计方式
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	freq := uint8(15)
+	for i, idx := range s.indexes(h) {
+		if s.rows[i][idx] < freq {
+			freq = s.rows[i][idx]
+		}
+	}
+	return freq
+}
+
+// hash returns the uint64 used to index the frequency sketch for key,
+// using Hasher if the caller set one, or FNV-1a over fmt.Sprint(key)
+// otherwise.
+// hash返回用于在频率草图中索引key的uint64值,如果调用方设置了Hasher就
+// 使用Hasher,否则对key的fmt.Sprint表示做FNV-1a哈希
+func (c *CacheOf[K, V]) hash(key K) uint64 {
+	if c.Hasher != nil {
+		return c.Hasher(key)
+	}
+	h := fnv.New64a()
+	fmt.Fprint(h, key)
+	return h.Sum64()
+}
+
+// ensureSketch lazily creates the frequency sketch, sized off MaxEntries
+// as the request asked for (roughly 10x MaxEntries counters per row).
+// ensureSketch惰性地创建频率草图,按MaxEntries来确定大小
+// (每行大约是MaxEntries的10倍个计数器)
+func (c *CacheOf[K, V]) ensureSketch() *countMinSketch {
+	if c.sketch == nil {
+		c.sketch = newCountMinSketch(c.MaxEntries * 10)
+	}
+	return c.sketch
+}
+
+// recordAccess feeds a key access into the TinyLFU frequency sketch.
+// recordAccess把一次key访问计入TinyLFU的频率草图
+func (c *CacheOf[K, V]) recordAccess(key K) {
+	c.ensureSketch().add(c.hash(key))
+}
+
+// admit reports whether candidate should be let in ahead of victim, the
+// current LRU-tail eviction candidate. It's rejected only if its
+// estimated frequency is strictly lower than the victim's, matching the
+// request's "reject admission if the newcomer's estimated frequency is
+// lower" rule.
+// admit判断candidate是否应该被准入、顶替掉victim(当前LRU尾部的淘汰候选)。
+// 只有当candidate的估计频率严格低于victim时才会被拒绝准入
+func (c *CacheOf[K, V]) admit(candidate K, victim *list.Element) bool {
+	s := c.ensureSketch()
+	candidateFreq := s.estimate(c.hash(candidate))
+	victimFreq := s.estimate(c.hash(victim.Value.(*entryOf[K, V]).key))
+	return candidateFreq >= victimFreq
+}
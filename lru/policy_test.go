@@ -0,0 +1,100 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPolicyLRUIsDefault(t *testing.T) {
+	c := NewOf[string, int](0)
+	if c.Policy != PolicyLRU {
+		t.Fatalf("Policy = %v, want PolicyLRU", c.Policy)
+	}
+}
+
+// TestPolicyTinyLFUResistsScan checks the scenario PolicyTinyLFU exists
+// for: a handful of frequently reused keys must survive a one-off scan of
+// cold keys that would flush them out of a pure-LRU cache.
+func TestPolicyTinyLFUResistsScan(t *testing.T) {
+	c := NewOf[string, int](0)
+	c.MaxEntries = 10
+	c.Policy = PolicyTinyLFU
+
+	hot := []string{"h0", "h1", "h2"}
+	for i, k := range hot {
+		c.Add(k, i)
+	}
+	// Warm up the hot keys' estimated frequency well past anything a
+	// one-off scan key can reach.
+	for i := 0; i < 50; i++ {
+		for _, k := range hot {
+			c.Get(k)
+		}
+	}
+	for i := 0; i < 7; i++ {
+		c.Add(fmt.Sprintf("cold%d", i), i)
+	}
+	if c.Len() != 10 {
+		t.Fatalf("Len() = %d, want 10", c.Len())
+	}
+
+	// A long scan of keys each touched once: under PolicyLRU this would
+	// eventually evict the hot keys as they age towards the tail.
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("scan%d", i), i)
+	}
+
+	for _, k := range hot {
+		if !c.Contains(k) {
+			t.Fatalf("hot key %q was evicted by the scan under PolicyTinyLFU", k)
+		}
+	}
+}
+
+func TestCountMinSketchEstimateTracksAdds(t *testing.T) {
+	s := newCountMinSketch(64)
+	h := uint64(12345)
+	if got := s.estimate(h); got != 0 {
+		t.Fatalf("estimate before any add = %d, want 0", got)
+	}
+	for i := 0; i < 5; i++ {
+		s.add(h)
+	}
+	if got := s.estimate(h); got != 5 {
+		t.Fatalf("estimate after 5 adds = %d, want 5", got)
+	}
+}
+
+func TestCountMinSketchSaturatesAndHalves(t *testing.T) {
+	s := newCountMinSketch(64)
+	h := uint64(1)
+	for i := 0; i < 20; i++ {
+		s.add(h)
+	}
+	if got := s.estimate(h); got != 15 {
+		t.Fatalf("estimate after 20 adds = %d, want saturated at 15", got)
+	}
+
+	for i := 0; i < s.resetThreshold; i++ {
+		s.add(uint64(i))
+	}
+	if got := s.estimate(h); got >= 15 {
+		t.Fatalf("estimate after reset threshold reached = %d, want halved below 15", got)
+	}
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// defaultShards is the shard count NewSharded falls back to when the
+// caller doesn't ask for a specific one.
+// defaultShards是调用方没有指定分片数时NewSharded使用的默认值
+const defaultShards = 16
+
+// ShardedCache is a concurrency-safe LRU cache composed of N independent
+// Cache shards, each guarded by its own sync.Mutex. Keys are routed to a
+// shard by hashing, so unrelated keys rarely contend on the same lock,
+// unlike a single Cache guarded by one global mutex.
+// ShardedCache是并发安全的LRU cache,由N个互相独立的Cache分片组成,
+// 每个分片有自己的sync.Mutex;key通过哈希路由到某个分片,这样不相关的key
+// 很少会竞争同一把锁,不像用一把全局锁保护单个Cache那样容易产生锁竞争
+type ShardedCache struct {
+	// DefaultTTL is the TTL Add gives new entries, mirroring CacheOf's
+	// field of the same name (zero means entries added through Add never
+	// expire). Set it before the cache is used concurrently; like every
+	// other field here, it isn't guarded by the shard mutexes.
+	// DefaultTTL是Add给新entry设置的TTL,和CacheOf同名字段的语义一致
+	// (零值表示通过Add添加的entry永不过期);应当在并发使用这个缓存之前
+	// 设置好它,和这里的其他字段一样,它不受分片锁保护
+	DefaultTTL time.Duration
+
+	shards []shard
+	hasher func(Key) uint64
+
+	janitorStop chan struct{}
+	janitorDone chan struct{}
+}
+
+type shard struct {
+	mu sync.Mutex
+	c  *Cache
+}
+
+// NewSharded creates a ShardedCache of shards Cache instances, each
+// allowing up to maxEntries items (the MaxEntries semantics of New apply
+// per shard, not to the whole ShardedCache). If shards is zero or
+// negative it defaults to defaultShards, and if hasher is nil keys are
+// hashed with FNV-1a over their fmt.Sprint representation.
+// NewSharded创建一个由shards个Cache分片组成的ShardedCache,每个分片最多
+// 存放maxEntries条数据(New的MaxEntries语义是针对每个分片的,而不是整个
+// ShardedCache);shards为0或负数时使用defaultShards,hasher为nil时
+// 使用对key的fmt.Sprint表示做FNV-1a哈希
+func NewSharded(maxEntries, shards int, hasher func(Key) uint64) *ShardedCache {
+	if shards <= 0 {
+		shards = defaultShards
+	}
+	if hasher == nil {
+		hasher = fnv1aHash
+	}
+	sc := &ShardedCache{
+		shards: make([]shard, shards),
+		hasher: hasher,
+	}
+	for i := range sc.shards {
+		sc.shards[i].c = New(maxEntries)
+	}
+	return sc
+}
+
+func fnv1aHash(key Key) uint64 {
+	h := fnv.New64a()
+	fmt.Fprint(h, key)
+	return h.Sum64()
+}
+
+func (sc *ShardedCache) shardFor(key Key) *shard {
+	return &sc.shards[sc.hasher(key)%uint64(len(sc.shards))]
+}
+
+// Add adds a value to the cache, using DefaultTTL as its expiration (zero
+// DefaultTTL means the entry never expires).
+func (sc *ShardedCache) Add(key Key, value interface{}) {
+	sc.AddWithTTL(key, value, sc.DefaultTTL)
+}
+
+// AddWithTTL adds a value to the cache with its own expiration, overriding
+// DefaultTTL for this entry. A zero or negative ttl means the entry never
+// expires. Besides Add with DefaultTTL set, this is the only way to give
+// a ShardedCache entries for StartJanitor to actively sweep.
+// AddWithTTL往缓存中增加一个值,并为这个entry单独指定过期时间,会覆盖
+// DefaultTTL;ttl为零值或负值表示永不过期。除了设置了DefaultTTL的Add之外,
+// 这是唯一能让StartJanitor有entry可以主动清理的添加方式
+func (sc *ShardedCache) AddWithTTL(key Key, value interface{}, ttl time.Duration) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.AddWithTTL(key, value, ttl)
+}
+
+// Get looks up a key's value from the cache. An entry whose TTL has
+// elapsed is treated as a miss and is evicted on the spot.
+func (sc *ShardedCache) Get(key Key) (value interface{}, ok bool) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Get(key)
+}
+
+// GetWithExpiration looks up a key's value from the cache and additionally
+// reports the time at which it will expire. The zero Time means the entry
+// has no TTL.
+func (sc *ShardedCache) GetWithExpiration(key Key) (value interface{}, expiration time.Time, ok bool) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.GetWithExpiration(key)
+}
+
+// Peek returns the value associated with key without updating its
+// recency within its shard.
+func (sc *ShardedCache) Peek(key Key) (value interface{}, ok bool) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Peek(key)
+}
+
+// Contains reports whether key is in the cache, without updating its
+// recency.
+func (sc *ShardedCache) Contains(key Key) bool {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.c.Contains(key)
+}
+
+// Remove removes the provided key from the cache.
+func (sc *ShardedCache) Remove(key Key) {
+	s := sc.shardFor(key)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.c.Remove(key)
+}
+
+// Len returns the total number of items across all shards.
+func (sc *ShardedCache) Len() int {
+	n := 0
+	for i := range sc.shards {
+		s := &sc.shards[i]
+		s.mu.Lock()
+		n += s.c.Len()
+		s.mu.Unlock()
+	}
+	return n
+}
+
+// Clear purges all stored items from every shard.
+func (sc *ShardedCache) Clear() {
+	for i := range sc.shards {
+		s := &sc.shards[i]
+		s.mu.Lock()
+		s.c.Clear()
+		s.mu.Unlock()
+	}
+}
+
+// StartJanitor starts a background goroutine that actively sweeps expired
+// entries (added via AddWithTTL or Add with DefaultTTL set) from every
+// shard every interval, taking each shard's own mutex for the duration of
+// its sweep so it can't race with Add/Get calls from other goroutines.
+// Calling StartJanitor again while one is already running is a no-op;
+// call StopJanitor first.
+// StartJanitor启动一个后台goroutine,按interval周期性地清理每个分片里
+// (通过AddWithTTL或设置了DefaultTTL的Add添加的)过期entry,清理期间会
+// 持有该分片自己的锁,因此不会和其他goroutine的Add/Get调用产生竞争。
+// 如果janitor已经在运行,再次调用StartJanitor不会有任何效果,需要先
+// StopJanitor
+func (sc *ShardedCache) StartJanitor(interval time.Duration) {
+	if sc.janitorStop != nil || interval <= 0 {
+		return
+	}
+	sc.janitorStop = make(chan struct{})
+	sc.janitorDone = make(chan struct{})
+	stop := sc.janitorStop
+	done := sc.janitorDone
+	go func() {
+		defer close(done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-t.C:
+				now := time.Now()
+				for i := range sc.shards {
+					s := &sc.shards[i]
+					s.mu.Lock()
+					s.c.sweepExpired(now)
+					s.mu.Unlock()
+				}
+			}
+		}
+	}()
+}
+
+// StopJanitor stops a janitor previously started with StartJanitor and
+// waits for its goroutine to exit. It is a no-op if no janitor is running.
+// StopJanitor停止之前StartJanitor启动的janitor,并等待其goroutine退出;
+// 如果没有janitor在运行,调用它不会有任何效果
+func (sc *ShardedCache) StopJanitor() {
+	if sc.janitorStop == nil {
+		return
+	}
+	close(sc.janitorStop)
+	<-sc.janitorDone
+	sc.janitorStop = nil
+	sc.janitorDone = nil
+}
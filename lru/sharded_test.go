@@ -0,0 +1,145 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestNewShardedDefaults(t *testing.T) {
+	sc := NewSharded(0, 0, nil)
+	if len(sc.shards) != defaultShards {
+		t.Fatalf("len(shards) = %d, want defaultShards (%d)", len(sc.shards), defaultShards)
+	}
+	if sc.hasher == nil {
+		t.Fatalf("hasher = nil, want fnv1aHash default")
+	}
+}
+
+func TestShardedCacheAddAndGet(t *testing.T) {
+	sc := NewSharded(0, 4, nil)
+	sc.Add("a", 1)
+	if v, ok := sc.Get("a"); !ok || v != 1 {
+		t.Fatalf("Get(a) = %v, %v; want 1, true", v, ok)
+	}
+	if sc.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", sc.Len())
+	}
+}
+
+func TestShardedCacheGetMiss(t *testing.T) {
+	sc := NewSharded(0, 4, nil)
+	if _, ok := sc.Get("missing"); ok {
+		t.Fatalf("Get(missing) ok = true, want false")
+	}
+}
+
+func TestShardedCachePeekDoesNotAffectEviction(t *testing.T) {
+	// A single-shard cache so Peek's ordering effect on that one shard's
+	// LRU list is observable, mirroring CacheOf's own Peek test.
+	sc := NewSharded(2, 1, nil)
+	sc.Add("a", 1)
+	sc.Add("b", 2)
+	if _, ok := sc.Peek("a"); !ok {
+		t.Fatalf("Peek(a) ok = false, want true")
+	}
+	sc.Add("c", 3)
+	if sc.Contains("a") {
+		t.Fatalf("a should have been evicted since Peek must not change recency")
+	}
+}
+
+func TestShardedCacheContainsAndRemove(t *testing.T) {
+	sc := NewSharded(0, 4, nil)
+	sc.Add("a", 1)
+	if !sc.Contains("a") {
+		t.Fatalf("Contains(a) = false, want true")
+	}
+	sc.Remove("a")
+	if sc.Contains("a") {
+		t.Fatalf("Contains(a) = true after Remove, want false")
+	}
+	if sc.Len() != 0 {
+		t.Fatalf("Len() = %d after Remove, want 0", sc.Len())
+	}
+}
+
+func TestShardedCacheClear(t *testing.T) {
+	sc := NewSharded(0, 4, nil)
+	for i := 0; i < 20; i++ {
+		sc.Add(fmt.Sprintf("k%d", i), i)
+	}
+	sc.Clear()
+	if sc.Len() != 0 {
+		t.Fatalf("Len() = %d after Clear, want 0", sc.Len())
+	}
+}
+
+func TestShardedCacheRespectsPerShardMaxEntries(t *testing.T) {
+	// A single shard so MaxEntries' per-shard semantics are directly
+	// observable rather than depending on hash distribution.
+	sc := NewSharded(2, 1, nil)
+	sc.Add("a", 1)
+	sc.Add("b", 2)
+	sc.Add("c", 3)
+	if sc.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2 (MaxEntries applies per shard)", sc.Len())
+	}
+}
+
+// TestShardedCacheConcurrentAccess hammers a ShardedCache from many
+// goroutines doing the full mixed read/write surface at once. It exists
+// to be run with go test -race: this is the concurrency safety the whole
+// type is for, and nothing else exercises it under contention.
+func TestShardedCacheConcurrentAccess(t *testing.T) {
+	sc := NewSharded(64, 8, nil)
+
+	const goroutines = 16
+	const opsPerGoroutine = 500
+
+	var wg sync.WaitGroup
+	wg.Add(goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < opsPerGoroutine; i++ {
+				key := fmt.Sprintf("k%d", (g*opsPerGoroutine+i)%32)
+				switch i % 5 {
+				case 0:
+					sc.Add(key, i)
+				case 1:
+					sc.Get(key)
+				case 2:
+					sc.Peek(key)
+				case 3:
+					sc.Contains(key)
+				case 4:
+					sc.Remove(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// The cache must still be in a usable, internally consistent state.
+	sc.Add("final", 1)
+	if v, ok := sc.Get("final"); !ok || v != 1 {
+		t.Fatalf("Get(final) = %v, %v; want 1, true", v, ok)
+	}
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"container/heap"
+	"container/list"
+	"time"
+)
+
+// EvictReason describes why an entry left the cache, reported to
+// OnEvictedReason.
+// EvictReason描述了entry被淘汰的原因,会通过OnEvictedReason上报
+type EvictReason int
+
+const (
+	// EvictReasonRemoved means the entry was removed by an explicit call
+	// to Remove.
+	// EvictReasonRemoved表示entry是被显式调用Remove移除的
+	EvictReasonRemoved EvictReason = iota
+	// EvictReasonCapacity means the entry was evicted by RemoveOldest,
+	// either to keep the cache within MaxEntries and MaxBytes or by an
+	// explicit call.
+	// EvictReasonCapacity表示entry是被RemoveOldest淘汰的,可能是为了满足
+	// MaxEntries和MaxBytes的限制,也可能是被显式调用的
+	EvictReasonCapacity
+	// EvictReasonExpired means the entry's TTL had elapsed, whether it
+	// was caught lazily by Get or actively by the janitor.
+	// EvictReasonExpired表示entry的TTL已经到期,可能是被Get惰性发现的,
+	// 也可能是被janitor主动清理的
+	EvictReasonExpired
+	// EvictReasonClear means the entry was dropped by a call to Clear.
+	// EvictReasonClear表示entry是被Clear清空缓存时丢弃的
+	EvictReasonClear
+)
+
+// expHeapOf is a min-heap, ordered by expiresAt, over the elements of a
+// CacheOf's ll that carry a TTL. It lets the janitor find and pop expired
+// entries in O(log n) per entry instead of scanning the whole cache.
+// expHeapOf是按expiresAt排序的小顶堆,堆里是CacheOf中ll上那些设置了TTL的
+// entry;有了它,janitor可以用O(log n)的代价逐个找到并弹出过期的entry,
+// 而不需要扫描整个缓存
+type expHeapOf[K comparable, V any] []*list.Element
+
+func (h expHeapOf[K, V]) Len() int { return len(h) }
+
+func (h expHeapOf[K, V]) Less(i, j int) bool {
+	return h[i].Value.(*entryOf[K, V]).expiresAt.Before(h[j].Value.(*entryOf[K, V]).expiresAt)
+}
+
+func (h expHeapOf[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].Value.(*entryOf[K, V]).heapIdx = i
+	h[j].Value.(*entryOf[K, V]).heapIdx = j
+}
+
+func (h *expHeapOf[K, V]) Push(x interface{}) {
+	ele := x.(*list.Element)
+	ele.Value.(*entryOf[K, V]).heapIdx = len(*h)
+	*h = append(*h, ele)
+}
+
+func (h *expHeapOf[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	ele := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	ele.Value.(*entryOf[K, V]).heapIdx = -1
+	return ele
+}
+
+func (h *expHeapOf[K, V]) push(ele *list.Element) {
+	heap.Push(h, ele)
+}
+
+func (h *expHeapOf[K, V]) fix(idx int) {
+	heap.Fix(h, idx)
+}
+
+func (h *expHeapOf[K, V]) remove(idx int) {
+	if idx < 0 || idx >= len(*h) {
+		return
+	}
+	heap.Remove(h, idx)
+}
+
+func (h expHeapOf[K, V]) peek() *list.Element {
+	if len(h) == 0 {
+		return nil
+	}
+	return h[0]
+}
+
+// sweepExpired pops entries off the expiry heap for as long as the
+// earliest one has already expired, giving an O(k log n) active-eviction
+// pass in the number of expirations k, rather than an O(n) scan of the
+// whole cache.
+//
+// CacheOf has no exported way to run this on a timer: doing so would race
+// with any other goroutine's Get/Add/Len on the same cache, since CacheOf
+// is not safe for concurrent access in the first place. ShardedCache's
+// StartJanitor is the supported way to sweep actively in the background;
+// it calls this under each shard's own mutex.
+// sweepExpired不断弹出堆顶,直到堆顶的entry还没有过期为止;
+// 这样一次清理的代价是O(k log n)(k为本次过期的数量),而不是扫描整个缓存的O(n)
+//
+// CacheOf没有提供按定时器调用它的导出方法:这么做会和同一个缓存上其他
+// goroutine的Get/Add/Len竞争,因为CacheOf本身就不是并发安全的。
+// ShardedCache的StartJanitor才是受支持的主动清理方式,它会在每个分片
+// 自己的锁保护下调用这个方法
+func (c *CacheOf[K, V]) sweepExpired(now time.Time) {
+	for {
+		ele := c.expHeap.peek()
+		if ele == nil {
+			return
+		}
+		en := ele.Value.(*entryOf[K, V])
+		if !en.expired(now) {
+			return
+		}
+		c.removeElement(ele, EvictReasonExpired)
+	}
+}
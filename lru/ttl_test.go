@@ -0,0 +1,101 @@
+/*
+Copyright 2013 Google Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+     http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lru
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetExpiresLazily(t *testing.T) {
+	var reason EvictReason
+	c := NewOf[string, int](0)
+	c.OnEvictedReason = func(key string, value int, r EvictReason) { reason = r }
+
+	c.AddWithTTL("a", 1, time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, ok := c.Get("a"); ok {
+		t.Fatalf("Get(a) ok = true after TTL elapsed, want false")
+	}
+	if reason != EvictReasonExpired {
+		t.Fatalf("reason = %v, want EvictReasonExpired", reason)
+	}
+	if c.Len() != 0 {
+		t.Fatalf("Len() = %d after lazy expiration, want 0", c.Len())
+	}
+}
+
+func TestAddWithTTLZeroNeverExpires(t *testing.T) {
+	c := NewOf[string, int](0)
+	c.Add("a", 1) // DefaultTTL is zero
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := c.Get("a"); !ok {
+		t.Fatalf("Get(a) ok = false, want true since a has no TTL")
+	}
+}
+
+func TestGetWithExpirationReportsDeadline(t *testing.T) {
+	c := NewOf[string, int](0)
+	before := time.Now()
+	c.AddWithTTL("a", 1, time.Hour)
+	_, expiration, ok := c.GetWithExpiration("a")
+	if !ok {
+		t.Fatalf("GetWithExpiration(a) ok = false, want true")
+	}
+	if !expiration.After(before) {
+		t.Fatalf("expiration = %v, want after %v", expiration, before)
+	}
+}
+
+// TestShardedCacheJanitorSweepsExpired exercises the only supported way to
+// actively sweep expired entries: CacheOf itself has no exported
+// StartJanitor, since running one on a bare CacheOf would race with
+// concurrent Get/Add/Len calls from other goroutines. ShardedCache's
+// StartJanitor sweeps each shard under that shard's own mutex instead.
+func TestShardedCacheJanitorSweepsExpired(t *testing.T) {
+	sc := NewSharded(0, 4, nil)
+	for i := 0; i < 8; i++ {
+		sc.AddWithTTL(fmt.Sprintf("k%d", i), i, time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	sc.StartJanitor(time.Millisecond)
+	defer sc.StopJanitor()
+
+	deadline := time.Now().Add(time.Second)
+	for sc.Len() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := sc.Len(); n != 0 {
+		t.Fatalf("ShardedCache.Len() = %d after janitor sweep, want 0", n)
+	}
+}
+
+// TestShardedCacheAddUsesDefaultTTL checks that Add honors DefaultTTL the
+// same way CacheOf's Add does, since that's the only other way (besides
+// AddWithTTL) to give StartJanitor something to sweep.
+func TestShardedCacheAddUsesDefaultTTL(t *testing.T) {
+	sc := NewSharded(0, 4, nil)
+	sc.DefaultTTL = time.Millisecond
+	sc.Add("a", 1)
+	time.Sleep(5 * time.Millisecond)
+	if _, ok := sc.Get("a"); ok {
+		t.Fatalf("Get(a) ok = true after DefaultTTL elapsed, want false")
+	}
+}